@@ -2,10 +2,9 @@ package easycontainers
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"math/rand"
-	"os/exec"
-	"strings"
 	"time"
 
 	"fmt"
@@ -14,9 +13,13 @@ import (
 	"go/build"
 	"net"
 	"os/signal"
-	"path/filepath"
 	"sync"
 	"syscall"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 const prefix = "easycontainers-"
@@ -24,6 +27,10 @@ const prefix = "easycontainers-"
 var (
 	getFreePortLock = &sync.Mutex{}
 	allocatedPorts  = map[int]struct{}{}
+
+	dockerClientOnce sync.Once
+	dockerClientInst *client.Client
+	dockerClientErr  error
 )
 
 func init() {
@@ -35,15 +42,6 @@ func init() {
 
 	WaitForCleanup()
 
-	// cleanup any outstanding sql files in temp
-	filepath.Walk(os.TempDir(), func(path string, info os.FileInfo, err error) error {
-		if strings.HasPrefix(info.Name(), prefix) {
-			os.Remove(path)
-		}
-
-		return nil
-	})
-
 	// try to cleanup containers if signaled to quit
 	signalCh := make(chan os.Signal, 1024)
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGKILL)
@@ -67,132 +65,153 @@ func GoPath() string {
 	return s
 }
 
-// CleanupAllContainers will stop all containers starting with prefix
+// dockerClient returns a Docker Engine API client shared by the whole package,
+// negotiating the API version with the daemon the first time it's called.
+func dockerClient() (*client.Client, error) {
+	dockerClientOnce.Do(func() {
+		dockerClientInst, dockerClientErr = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	})
+
+	return dockerClientInst, dockerClientErr
+}
+
+// CleanupAllContainers will stop and remove all containers starting with prefix
 func CleanupAllContainers() error {
-	cmd := exec.Command(
-		"/bin/bash",
-		"-c",
-		fmt.Sprintf(`docker stop $(docker ps --filter="name=%s" --format="{{.ID}}")`, prefix),
-	)
+	cli, err := dockerClient()
+	if err != nil {
+		return err
+	}
 
-	var b bytes.Buffer
-	cmd.Stderr = &b
+	ctx := context.Background()
 
-	err := cmd.Run()
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", prefix)),
+	})
 	if err != nil {
-		return fmt.Errorf("error in command : %s -- %s", err, b.String())
+		return fmt.Errorf("error listing containers : %s", err)
+	}
+
+	for _, c := range containers {
+		if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("error removing container %s : %s", c.ID, err)
+		}
 	}
 
-	return err
+	return nil
 }
 
 // WaitForCleanup checks every second if there are any easycontainers containers still
 // live, and exits when there aren't, or when the timeout occurrs -- whichever comes first
 func WaitForCleanup() error {
-	cmd := exec.Command(
-		"/bin/bash",
-		"-c",
-		fmt.Sprintf(
-			`while [ "$(docker ps --filter="name=%s" --format="{{.ID}}")" ]; do echo 'waiting for cleanup to finish'; sleep 1; done`,
-			prefix,
-		),
-	)
-
-	return RunCommandWithTimeout(cmd, 1*time.Minute)
-}
+	cli, err := dockerClient()
+	if err != nil {
+		return err
+	}
 
-// CleanupContainer stops the container with the specified name.
-func CleanupContainer(name string) error {
-	cmd := exec.Command(
-		"/bin/bash",
-		"-c",
-		fmt.Sprintf(`docker stop $(docker ps --filter="name=^/%s$" --format="{{.ID}}")`, name),
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
 
-	var b bytes.Buffer
-	cmd.Stderr = &b
+	for {
+		containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+			Filters: filters.NewArgs(filters.Arg("name", prefix)),
+		})
+		if err != nil {
+			return fmt.Errorf("error listing containers : %s", err)
+		}
+
+		if len(containers) == 0 {
+			return nil
+		}
+
+		fmt.Println("waiting for cleanup to finish")
+
+		select {
+		case <-ctx.Done():
+			return errors.New("container timed out")
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
 
-	err := cmd.Run()
+// CleanupContainer stops and removes the container with the specified name.
+func CleanupContainer(name string) error {
+	cli, err := dockerClient()
 	if err != nil {
-		return fmt.Errorf("error in command : %s -- %s", err, b.String())
+		return err
+	}
+
+	err = cli.ContainerRemove(context.Background(), name, types.ContainerRemoveOptions{Force: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return fmt.Errorf("error removing container %s : %s", name, err)
 	}
 
-	return err
+	return nil
 }
 
-// Logs runs the docker logs command on the specified container and returns the output
+// Logs returns the stdout and stderr logs for the specified container.
 func Logs(name string) string {
-	cmd := exec.Command(
-		"docker",
-		"logs",
-		name,
-	)
+	cli, err := dockerClient()
+	if err != nil {
+		return ""
+	}
 
-	var outputBuf bytes.Buffer
-	cmd.Stderr = &outputBuf
-	cmd.Stdout = &outputBuf
+	rc, err := cli.ContainerLogs(context.Background(), name, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
 
-	cmd.Run()
+	// containers are created without a tty, so the daemon multiplexes stdout and
+	// stderr into a single stream framed with 8-byte stdcopy headers; it has to
+	// be demuxed before the bytes mean anything
+	var b bytes.Buffer
+	stdcopy.StdCopy(&b, &b, rc)
 
-	return outputBuf.String()
+	return b.String()
 }
 
-// RunCommandWithTimeout will execute the specified cmd, but will timeout and
-// return and error after 1 minute.
-func RunCommandWithTimeout(cmd *exec.Cmd, timeout time.Duration) error {
-	finish := make(chan error)
-	timer := time.NewTimer(timeout)
-
-	go func() {
-		var err error
+// execInContainer runs cmd inside the named container via the Engine API and
+// returns its combined stdout/stderr output. It returns an error if the exec
+// itself couldn't be created/attached, or if the command exited non-zero.
+func execInContainer(ctx context.Context, containerName string, cmd []string) (string, error) {
+	cli, err := dockerClient()
+	if err != nil {
+		return "", err
+	}
 
-		defer func() {
-			finish <- err
-		}()
+	created, err := cli.ContainerExecCreate(ctx, containerName, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating exec for %s : %s", containerName, err)
+	}
 
-		var b bytes.Buffer
-		cmd.Stderr = &b
-		cmd.Stdout = os.Stdout
+	attached, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return "", fmt.Errorf("error attaching exec for %s : %s", containerName, err)
+	}
+	defer attached.Close()
 
-		err = cmd.Run()
-		if err != nil {
-			err = fmt.Errorf("error in command : %s -- %s", err, b.String())
-			return
-		}
-	}()
+	// execs are also attached without a tty, so demux the same way as Logs
+	var b bytes.Buffer
+	stdcopy.StdCopy(&b, &b, attached.Reader)
 
-	select {
-	case err := <-finish:
-		if err != nil {
-			return err
-		}
-	case <-timer.C:
-		return errors.New("container timed out")
+	inspected, err := cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return b.String(), fmt.Errorf("error inspecting exec for %s : %s", containerName, err)
 	}
 
-	return nil
-}
-
-func cmdForContainer(name string, cmd *exec.Cmd) *exec.Cmd {
-	return exec.Command(
-		"docker",
-		"exec",
-		name,
-		"/bin/bash",
-		"-c",
-		strings.Join(cmd.Args, " "),
-	)
-}
+	if inspected.ExitCode != 0 {
+		return b.String(), fmt.Errorf("exec %v in %s exited with code %d : %s", cmd, containerName, inspected.ExitCode, b.String())
+	}
 
-func strCmdForContainer(name string, str string) *exec.Cmd {
-	return exec.Command(
-		"docker",
-		"exec",
-		name,
-		"/bin/bash",
-		"-c",
-		str,
-	)
+	return b.String(), nil
 }
 
 func getFreePort() (int, error) {
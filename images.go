@@ -0,0 +1,118 @@
+package easycontainers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ImagePullPolicy controls when Container pulls an image before creating a
+// container from it.
+type ImagePullPolicy string
+
+const (
+	// PullAlways always pulls the image, even if it's already present locally.
+	PullAlways ImagePullPolicy = "Always"
+	// PullIfNotPresent only pulls the image if it isn't already present locally.
+	// This is the default.
+	PullIfNotPresent ImagePullPolicy = "IfNotPresent"
+	// PullNever never pulls the image; it must already be present locally.
+	PullNever ImagePullPolicy = "Never"
+)
+
+// RegistryAuth holds credentials for a private image registry. It is encoded
+// and passed as the X-Registry-Auth header when pulling an image.
+type RegistryAuth struct {
+	Username      string
+	Password      string
+	ServerAddress string
+}
+
+// encode base64-JSON-encodes a, in the form the Engine API expects for the
+// X-Registry-Auth header.
+func (a RegistryAuth) encode() (string, error) {
+	b, err := json.Marshal(types.AuthConfig{
+		Username:      a.Username,
+		Password:      a.Password,
+		ServerAddress: a.ServerAddress,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+var (
+	defaultImagesLock = &sync.Mutex{}
+	defaultImages     = map[string]string{
+		"mysql": "mysql:8.0",
+	}
+)
+
+// SetDefaultImage overrides the default image reference used for the given kind
+// (e.g. "mysql") by container types that don't have an explicit Image set. This
+// lets a whole test suite be pointed at an internal registry mirror in one place.
+func SetDefaultImage(kind, ref string) {
+	defaultImagesLock.Lock()
+	defer defaultImagesLock.Unlock()
+
+	defaultImages[kind] = ref
+}
+
+func defaultImage(kind string) string {
+	defaultImagesLock.Lock()
+	defer defaultImagesLock.Unlock()
+
+	return defaultImages[kind]
+}
+
+// pullImage pulls ref according to policy, authenticating with auth if it's
+// non-nil. A nil/zero policy is treated as PullIfNotPresent.
+func pullImage(ctx context.Context, ref string, policy ImagePullPolicy, auth *RegistryAuth) error {
+	cli, err := dockerClient()
+	if err != nil {
+		return err
+	}
+
+	if policy == "" {
+		policy = PullIfNotPresent
+	}
+
+	if policy == PullNever {
+		return nil
+	}
+
+	if policy == PullIfNotPresent {
+		if _, _, err := cli.ImageInspectWithRaw(ctx, ref); err == nil {
+			return nil
+		}
+	}
+
+	opts := types.ImagePullOptions{}
+
+	if auth != nil {
+		encoded, err := auth.encode()
+		if err != nil {
+			return fmt.Errorf("error encoding registry auth : %s", err)
+		}
+
+		opts.RegistryAuth = encoded
+	}
+
+	rc, err := cli.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return fmt.Errorf("error pulling %s : %s", ref, err)
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(ioutil.Discard, rc)
+
+	return err
+}
@@ -0,0 +1,57 @@
+package easycontainers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestRegistryAuthEncode(t *testing.T) {
+	auth := RegistryAuth{
+		Username:      "user",
+		Password:      "pass",
+		ServerAddress: "registry.example.com",
+	}
+
+	encoded, err := auth.encode()
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+
+	raw, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("encode() did not produce valid base64url: %v", err)
+	}
+
+	var decoded struct {
+		Username      string
+		Password      string
+		ServerAddress string
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("encode() did not produce valid JSON: %v", err)
+	}
+
+	if decoded.Username != auth.Username || decoded.Password != auth.Password || decoded.ServerAddress != auth.ServerAddress {
+		t.Fatalf("encode() round-tripped to %+v, want %+v", decoded, auth)
+	}
+}
+
+func TestSetDefaultImage(t *testing.T) {
+	original := defaultImage("mysql")
+	defer SetDefaultImage("mysql", original)
+
+	if got := defaultImage("mysql"); got != "mysql:8.0" {
+		t.Fatalf("defaultImage(\"mysql\") = %q, want %q", got, "mysql:8.0")
+	}
+
+	SetDefaultImage("mysql", "registry.internal/mysql:8.0")
+
+	if got := defaultImage("mysql"); got != "registry.internal/mysql:8.0" {
+		t.Fatalf("defaultImage(\"mysql\") after SetDefaultImage = %q, want %q", got, "registry.internal/mysql:8.0")
+	}
+
+	if got := defaultImage("unknown-kind"); got != "" {
+		t.Fatalf("defaultImage(\"unknown-kind\") = %q, want empty string", got)
+	}
+}
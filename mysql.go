@@ -1,15 +1,21 @@
 package easycontainers
 
 import (
+	"archive/tar"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
-	"os/exec"
 	"path"
+	"strconv"
 	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 )
 
 // MySQL is a container using the official mysql docker image.
@@ -18,11 +24,34 @@ import (
 // the file when initializing the container.
 //
 // Query is a string of SQL. If set, it will run the sql when initializing the container.
+//
+// WaitingFor, if set, determines when the container is considered ready instead of the
+// default behavior of polling a sentinel table via docker exec.
+//
+// BindMounts and Tmpfs mount host paths and tmpfs mounts into the container, keyed by
+// the path inside the container. Env adds additional environment variables alongside
+// MYSQL_ROOT_PASSWORD. NetworkMode joins the container to an existing docker network
+// (e.g. so it can be reached by name from another container), and NetworkAliases
+// registers additional names for the container on networks it joins, keyed by network
+// name.
+//
+// Image defaults to the "mysql" kind's default image (see SetDefaultImage), which is
+// mysql:8.0 unless overridden. ImagePullPolicy defaults to PullIfNotPresent.
+// RegistryAuth, if set, authenticates the pull against a private registry.
 type MySQL struct {
-	ContainerName string
-	Port          int
-	Path          string
-	Query         string
+	ContainerName   string
+	Port            int
+	Path            string
+	Query           string
+	WaitingFor      WaitStrategy
+	BindMounts      map[string]string
+	Tmpfs           map[string]string
+	Env             map[string]string
+	NetworkMode     string
+	NetworkAliases  map[string][]string
+	Image           string
+	ImagePullPolicy ImagePullPolicy
+	RegistryAuth    *RegistryAuth
 }
 
 // NewMySQL returns a new instance of MySQL and the port it will be using.
@@ -52,22 +81,67 @@ func (m *MySQL) Container(f func() error) error {
 	CleanupContainer(m.ContainerName) // catch containers that previous cleanup missed
 	defer CleanupContainer(m.ContainerName)
 
-	var cmdList []*exec.Cmd
-
-	runContainerCmd := exec.Command(
-		"docker",
-		"run",
-		"--rm",
-		"-p",
-		fmt.Sprintf("%d:3306", m.Port),
-		"--name",
-		m.ContainerName,
-		"-e",
-		"MYSQL_ROOT_PASSWORD=pass",
-		"-d",
-		"mysql:latest",
-	)
-	cmdList = append(cmdList, runContainerCmd)
+	cli, err := dockerClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	image := m.Image
+	if image == "" {
+		image = defaultImage("mysql")
+	}
+
+	if err := pullImage(ctx, image, m.ImagePullPolicy, m.RegistryAuth); err != nil {
+		return err
+	}
+
+	containerPort := nat.Port("3306/tcp")
+
+	env := []string{"MYSQL_ROOT_PASSWORD=pass"}
+	for k, v := range m.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	var binds []string
+	for containerPath, hostPath := range m.BindMounts {
+		binds = append(binds, fmt.Sprintf("%s:%s", hostPath, containerPath))
+	}
+
+	hostConfig := &container.HostConfig{
+		PortBindings: nat.PortMap{
+			containerPort: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: strconv.Itoa(m.Port)}},
+		},
+		Binds:       binds,
+		Tmpfs:       m.Tmpfs,
+		NetworkMode: container.NetworkMode(m.NetworkMode),
+		AutoRemove:  true,
+	}
+
+	var networkingConfig *network.NetworkingConfig
+	if m.NetworkMode != "" {
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				m.NetworkMode: {Aliases: m.NetworkAliases[m.NetworkMode]},
+			},
+		}
+	}
+
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:        image,
+		Env:          env,
+		ExposedPorts: nat.PortSet{containerPort: struct{}{}},
+		Labels:       map[string]string{sessionLabel: SessionID()},
+	}, hostConfig, networkingConfig, nil, m.ContainerName)
+	if err != nil {
+		return fmt.Errorf("error creating container : %s", err)
+	}
+
+	if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("error starting container : %s", err)
+	}
 
 	var sql string
 
@@ -86,63 +160,86 @@ func (m *MySQL) Container(f func() error) error {
 		sql += "; " + m.Query
 	}
 
+	if m.WaitingFor == nil {
+		// we create the table mysql.z_z_(id integer) after all the other sql has been run
+		// so that we can query the table to see if all the startup sql is finished running,
+		// which means that the container if fully initialized
+		sql += ";CREATE TABLE mysql.z_z_(id integer);"
+	}
+
 	if sql != "" {
-		file, err := ioutil.TempFile(os.TempDir(), prefix+"*.sql")
+		tarball, err := tarFile("init.sql", []byte(sql))
 		if err != nil {
 			return err
 		}
-		defer file.Close()
-		defer os.Remove(file.Name())
 
-		err = os.Chmod(file.Name(), 0777)
+		err = cli.CopyToContainer(ctx, created.ID, "/docker-entrypoint-initdb.d", tarball, types.CopyToContainerOptions{})
 		if err != nil {
-			return err
+			return fmt.Errorf("error copying startup sql to container : %s", err)
 		}
+	}
 
-		// we create the table mysql.z_z_(id integer) after all the other sql has been run
-		// so that we can query the table to see if all the startup sql is finished running,
-		// which means that the container if fully initialized
-		_, err = io.Copy(file, bytes.NewBufferString(sql+";CREATE TABLE mysql.z_z_(id integer);"))
+	// the wait phase gets its own timeout, separate from pull/create/start/copy
+	// above, so a slow image pull on a fresh host doesn't eat into the time
+	// mysql has to finish initializing.
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer waitCancel()
+
+	if m.WaitingFor != nil {
+		if err := m.WaitingFor.WaitUntilReady(waitCtx, m.ContainerName, m.Port); err != nil {
+			return errWithLogs(m.ContainerName, err)
+		}
+	} else {
+		_, err := execInContainer(
+			waitCtx,
+			m.ContainerName,
+			[]string{
+				"/bin/bash", "-c",
+				"until (mysql -uroot -ppass -e 'select \"initialization table found\" from mysql.z_z_ limit 1') do echo 'waiting for mysql to be up'; sleep 1; done; sleep 3;",
+			},
+		)
 		if err != nil {
-			return err
+			return errWithLogs(m.ContainerName, err)
 		}
+	}
 
-		file.Close()
+	fmt.Println("successfully created mysql container")
 
-		addStartupSQLFileCmd := exec.Command(
-			"/bin/bash",
-			"-c",
-			fmt.Sprintf(
-				`docker cp %s $(docker ps --filter="name=^/%s$" --format="{{.ID}}"):/docker-entrypoint-initdb.d`,
-				file.Name(),
-				m.ContainerName,
-			),
-		)
-		cmdList = append(cmdList, addStartupSQLFileCmd)
-	}
+	return f()
+}
 
-	waitForInitializeCmd := strCmdForContainer(
-		m.ContainerName,
-		"until (mysql -uroot -ppass -e 'select \"initialization table found\" from mysql.z_z_ limit 1') do echo 'waiting for mysql to be up'; sleep 1; done; sleep 3;",
-	)
-	cmdList = append(cmdList, waitForInitializeCmd)
+// tarFile builds a single-file tar archive suitable for CopyToContainer.
+func tarFile(name string, contents []byte) (io.Reader, error) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
 
-	for _, c := range cmdList {
-		err := RunCommandWithTimeout(c, 1*time.Minute)
-		if err != nil {
-			// I'm showing the logs for this container specifically because if there is
-			// a sql error on startup, it won't return from stderr, it will only show
-			// up in the logs
-			logs := Logs(m.ContainerName)
-			if logs != "" {
-				err = errors.New(fmt.Sprintln(err, "", " -- CONTAINER LOGS -- ", "", logs))
-			}
+	err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0777,
+		Size: int64(len(contents)),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			return err
-		}
+	if _, err := tw.Write(contents); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("successfully created mysql container")
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
 
-	return f()
+	return &b, nil
+}
+
+// errWithLogs wraps err with the logs for name, since startup failures often only
+// show up in the container logs rather than on stderr.
+func errWithLogs(name string, err error) error {
+	logs := Logs(name)
+	if logs != "" {
+		err = errors.New(fmt.Sprintln(err, "", " -- CONTAINER LOGS -- ", "", logs))
+	}
+
+	return err
 }
@@ -0,0 +1,142 @@
+package easycontainers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// SkipReaper disables the reaper sidecar container. Set this in CI environments
+// that already guarantee containers are cleaned up between runs.
+var SkipReaper bool
+
+// ReaperImage is the docker image used to run the reaper sidecar container. It
+// must have a shell, the docker CLI, and nc (netcat) available, since the
+// reaper's heartbeat check shells out to `nc -z`. The default, docker:cli, has
+// all three; a custom image missing nc will fail its heartbeat check on every
+// iteration and tear down the session's containers almost immediately.
+var ReaperImage = "docker:cli"
+
+// sessionLabel is applied, along with the current session id, to every container
+// this process creates so the reaper knows what it's responsible for.
+const sessionLabel = "easycontainers.session"
+
+var (
+	reaperOnce sync.Once
+	sessionID  string
+)
+
+// SessionID returns the id used to label every container this process creates.
+// The id is generated, and the reaper sidecar started unless SkipReaper is set,
+// on the first call to SessionID; creating a container makes that call.
+func SessionID() string {
+	ensureReaper()
+	return sessionID
+}
+
+// ensureReaper generates the session id and, unless SkipReaper is set, starts the
+// reaper sidecar container the first time it's called. Later calls are a no-op.
+//
+// The reaper watches a TCP heartbeat from this process: it repeatedly dials back
+// into the process over the docker bridge, and once the dial has failed for
+// several seconds in a row -- meaning the process exited, however abnormally --
+// it stops and removes every container carrying this session's label.
+func ensureReaper() {
+	reaperOnce.Do(func() {
+		sessionID = newSessionID()
+
+		if SkipReaper {
+			return
+		}
+
+		heartbeatPort, err := startHeartbeatListener()
+		if err != nil {
+			fmt.Println("easycontainers: failed to start reaper:", err)
+			return
+		}
+
+		script := fmt.Sprintf(
+			`fails=0; while [ "$fails" -lt 5 ]; do if nc -z host.docker.internal %d; then fails=0; else fails=$((fails+1)); fi; sleep 1; done; `+
+				`docker stop $(docker ps -q --filter "label=%s=%s") 2>/dev/null; docker rm -f $(docker ps -aq --filter "label=%s=%s") 2>/dev/null`,
+			heartbeatPort, sessionLabel, sessionID, sessionLabel, sessionID,
+		)
+
+		cli, err := dockerClient()
+		if err != nil {
+			fmt.Println("easycontainers: failed to start reaper:", err)
+			return
+		}
+
+		ctx := context.Background()
+
+		rc, err := cli.ImagePull(ctx, ReaperImage, types.ImagePullOptions{})
+		if err == nil {
+			io.Copy(ioutil.Discard, rc)
+			rc.Close()
+		}
+
+		created, err := cli.ContainerCreate(ctx, &container.Config{
+			Image: ReaperImage,
+			Cmd:   []string{"sh", "-c", script},
+		}, &container.HostConfig{
+			AutoRemove: true,
+			ExtraHosts: []string{"host.docker.internal:host-gateway"},
+			Mounts: []mount.Mount{{
+				Type:   mount.TypeBind,
+				Source: "/var/run/docker.sock",
+				Target: "/var/run/docker.sock",
+			}},
+		}, nil, nil, prefix+"reaper-"+sessionID)
+		if err != nil {
+			fmt.Println("easycontainers: failed to start reaper:", err)
+			return
+		}
+
+		if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+			fmt.Println("easycontainers: failed to start reaper:", err)
+		}
+	})
+}
+
+// startHeartbeatListener binds a TCP listener that the reaper sidecar dials
+// back into to confirm this process is still alive, and returns the port it's
+// listening on. The listener is bound on all interfaces rather than just
+// loopback, since the sidecar connects over the docker bridge (via
+// host.docker.internal) rather than from 127.0.0.1 -- a loopback-only listener
+// would never see those connections, and the reaper would wrongly conclude the
+// parent process had died and tear down the caller's own containers.
+func startHeartbeatListener() (port int, err error) {
+	listener, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		return 0, err
+	}
+
+	// accept and drop every connection -- the reaper only cares whether the
+	// listener is still accepting, which it is for as long as this process is
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,43 @@
+package easycontainers
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+// TestStartHeartbeatListenerAcceptsNonLoopback proves the heartbeat listener can
+// be reached from an address other than loopback, which is how the reaper
+// sidecar actually dials back in (over the docker bridge via
+// host.docker.internal, not 127.0.0.1).
+func TestStartHeartbeatListenerAcceptsNonLoopback(t *testing.T) {
+	port, err := startHeartbeatListener()
+	if err != nil {
+		t.Fatalf("startHeartbeatListener() error = %v", err)
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatalf("net.InterfaceAddrs() error = %v", err)
+	}
+
+	var nonLoopback net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		nonLoopback = ipNet.IP
+		break
+	}
+
+	if nonLoopback == nil {
+		t.Skip("no non-loopback IPv4 address available to dial from")
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(nonLoopback.String(), strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("dialing heartbeat listener via %s failed, meaning it's still loopback-only: %v", nonLoopback, err)
+	}
+	conn.Close()
+}
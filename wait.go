@@ -0,0 +1,190 @@
+package easycontainers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// WaitStrategy determines when a container is considered ready to accept
+// connections. Implementations should block until the container is ready,
+// ctx is cancelled, or they give up and return an error.
+type WaitStrategy interface {
+	WaitUntilReady(ctx context.Context, containerName string, port int) error
+}
+
+// waitStrategyFunc adapts a function to a WaitStrategy.
+type waitStrategyFunc func(ctx context.Context, containerName string, port int) error
+
+func (f waitStrategyFunc) WaitUntilReady(ctx context.Context, containerName string, port int) error {
+	return f(ctx, containerName, port)
+}
+
+// WaitForSQL returns a WaitStrategy that opens a database/sql connection to the
+// container using driver and the DSN returned by dsn, and waits until a "SELECT 1"
+// query against that connection succeeds.
+func WaitForSQL(driver string, dsn func(port int) string) WaitStrategy {
+	return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+		db, err := sql.Open(driver, dsn(port))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		for {
+			_, err := db.ExecContext(ctx, "SELECT 1")
+			if err == nil {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for sql ping on %s: %w", containerName, ctx.Err())
+			case <-time.After(500 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// WaitForLog returns a WaitStrategy that streams the container's logs and succeeds
+// once pattern has matched at least occurrences lines.
+func WaitForLog(pattern string, occurrences int) WaitStrategy {
+	re := regexp.MustCompile(pattern)
+
+	return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+		cli, err := dockerClient()
+		if err != nil {
+			return err
+		}
+
+		rc, err := cli.ContainerLogs(ctx, containerName, types.ContainerLogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Follow:     true,
+		})
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		// containers are created without a tty, so stdout and stderr arrive
+		// multiplexed with stdcopy headers; demux into a pipe so the scanner
+		// below only ever sees real log lines
+		pr, pw := io.Pipe()
+		go func() {
+			_, err := stdcopy.StdCopy(pw, pw, rc)
+			pw.CloseWithError(err)
+		}()
+		// countMatchingLines may return before the copy goroutine has
+		// finished writing (e.g. once occurrences is reached); close pr so a
+		// pending pw.Write unblocks with io.ErrClosedPipe instead of leaking
+		// the goroutine and the underlying log connection.
+		defer pr.Close()
+
+		found, err := countMatchingLines(pr, re, occurrences)
+		if err != nil {
+			return err
+		}
+
+		if found < occurrences {
+			return fmt.Errorf("log pattern %q for %s matched %d times, wanted %d", pattern, containerName, found, occurrences)
+		}
+
+		return nil
+	})
+}
+
+// countMatchingLines scans r line by line, counting lines that match re, and
+// returns early once occurrences matches have been found.
+func countMatchingLines(r io.Reader, re *regexp.Regexp, occurrences int) (int, error) {
+	found := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if re.MatchString(scanner.Text()) {
+			found++
+			if found >= occurrences {
+				return found, nil
+			}
+		}
+	}
+
+	return found, scanner.Err()
+}
+
+// WaitForListeningPort returns a WaitStrategy that waits until a TCP dial to the
+// mapped host port succeeds. If port is 0, the port passed to WaitUntilReady is used.
+func WaitForListeningPort(port int) WaitStrategy {
+	return waitStrategyFunc(func(ctx context.Context, containerName string, containerPort int) error {
+		p := port
+		if p == 0 {
+			p = containerPort
+		}
+
+		for {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", fmt.Sprintf("localhost:%d", p))
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for %s to listen on port %d: %w", containerName, p, ctx.Err())
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// WaitForHTTP returns a WaitStrategy that waits until a GET request against
+// http://localhost:<port><path> returns statusCode.
+func WaitForHTTP(path string, statusCode int) WaitStrategy {
+	return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+		url := fmt.Sprintf("http://localhost:%d%s", port, path)
+
+		for {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == statusCode {
+					return nil
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for %s to respond on %s: %w", containerName, url, ctx.Err())
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// WaitAll returns a WaitStrategy that waits for each of strategies in order,
+// succeeding only once all of them do.
+func WaitAll(strategies ...WaitStrategy) WaitStrategy {
+	return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+		for _, s := range strategies {
+			if err := s.WaitUntilReady(ctx, containerName, port); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
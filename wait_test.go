@@ -0,0 +1,157 @@
+package easycontainers
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountMatchingLines(t *testing.T) {
+	re := regexp.MustCompile(`ready`)
+
+	r := strings.NewReader("starting up\nstill starting\nready\nready\nready\n")
+
+	found, err := countMatchingLines(r, re, 2)
+	if err != nil {
+		t.Fatalf("countMatchingLines() error = %v", err)
+	}
+
+	if found != 2 {
+		t.Fatalf("countMatchingLines() found = %d, want 2 (should stop early once occurrences is hit)", found)
+	}
+}
+
+func TestCountMatchingLinesNotEnough(t *testing.T) {
+	re := regexp.MustCompile(`ready`)
+
+	r := strings.NewReader("starting up\nstill starting\n")
+
+	found, err := countMatchingLines(r, re, 1)
+	if err != nil {
+		t.Fatalf("countMatchingLines() error = %v", err)
+	}
+
+	if found != 0 {
+		t.Fatalf("countMatchingLines() found = %d, want 0", found)
+	}
+}
+
+func TestWaitForListeningPort(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := WaitForListeningPort(port).WaitUntilReady(ctx, "unused", 0); err != nil {
+		t.Fatalf("WaitUntilReady() error = %v", err)
+	}
+}
+
+func TestWaitForListeningPortUsesArgWhenZero(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := WaitForListeningPort(0).WaitUntilReady(ctx, "unused", port); err != nil {
+		t.Fatalf("WaitUntilReady() error = %v", err)
+	}
+}
+
+func TestWaitForListeningPortTimesOut(t *testing.T) {
+	// find a port nothing is listening on
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := WaitForListeningPort(port).WaitUntilReady(ctx, "unused", 0); err == nil {
+		t.Fatal("WaitUntilReady() error = nil, want timeout error")
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	var calls []string
+
+	ok := func(name string) WaitStrategy {
+		return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+			calls = append(calls, name)
+			return nil
+		})
+	}
+
+	err := WaitAll(ok("a"), ok("b"), ok("c")).WaitUntilReady(context.Background(), "unused", 0)
+	if err != nil {
+		t.Fatalf("WaitUntilReady() error = %v", err)
+	}
+
+	if strings.Join(calls, ",") != "a,b,c" {
+		t.Fatalf("WaitAll() ran strategies in order %v, want a,b,c", calls)
+	}
+}
+
+func TestWaitAllStopsOnFirstError(t *testing.T) {
+	var calls []string
+
+	failing := errFn("boom")
+
+	record := func(name string, err error) WaitStrategy {
+		return waitStrategyFunc(func(ctx context.Context, containerName string, port int) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+
+	err := WaitAll(record("a", nil), record("b", failing), record("c", nil)).WaitUntilReady(context.Background(), "unused", 0)
+	if err == nil {
+		t.Fatal("WaitUntilReady() error = nil, want error from strategy b")
+	}
+
+	if strings.Join(calls, ",") != "a,b" {
+		t.Fatalf("WaitAll() ran strategies %v, want a,b (should stop after b fails)", calls)
+	}
+}
+
+type errFn string
+
+func (e errFn) Error() string { return string(e) }